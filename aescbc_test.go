@@ -0,0 +1,346 @@
+package aescbc
+
+import (
+	"bytes"
+	"testing"
+)
+
+// plaintextLengths exercises block-aligned and, more importantly,
+// non-block-aligned sizes: it was a non-block-aligned Copy() of plaintext
+// like this that silently duplicated the trailing bytes before Write()'s
+// return value was fixed to report the length it actually consumed.
+var plaintextLengths = []int{0, 1, 15, 16, 17, 37, 61, 100, 1000}
+
+func testPlaintext(n int) []byte {
+	p := make([]byte, n)
+	for i := range p {
+		p[i] = byte(i)
+	}
+	return p
+}
+
+// cbcPadders covers all three Padder implementations this package ships, so
+// a regression in one (an off-by-one in a trailing-byte scan, say) shows up
+// here rather than only in the default PKCS7 path.
+var cbcPadders = []Padder{Pkcs7Padder{}, Iso7816Padder{}, AnsiX923Padder{}}
+
+// cbcKeySizes covers every AES key size NewAESCBCEncryptorWithKeySize
+// accepts, so a wrong slice bound on the 16/24-byte paths doesn't only show
+// up on the default 32-byte (AES-256) path.
+var cbcKeySizes = []int{128, 192, 256}
+
+func TestAESCBCRoundtrip(t *testing.T) {
+	for _, padder := range cbcPadders {
+		for _, bits := range cbcKeySizes {
+			for _, n := range plaintextLengths {
+				plaintext := testPlaintext(n)
+
+				enc, err := NewAESCBCEncryptorWithKeySize(bits, WithPadder(padder))
+				if err != nil {
+					t.Fatalf("%T %d-bit len %d: NewAESCBCEncryptorWithKeySize: %v", padder, bits, n, err)
+				}
+				if len(enc.AESKey) != bits/8 {
+					t.Fatalf("%T %d-bit: AESKey is %d bytes", padder, bits, len(enc.AESKey))
+				}
+
+				var ciphertext bytes.Buffer
+				if _, err := enc.Copy(&ciphertext, bytes.NewReader(plaintext)); err != nil {
+					t.Fatalf("%T %d-bit len %d: encrypt Copy: %v", padder, bits, n, err)
+				}
+
+				dec, err := NewAESCBCDecryptor(enc.AESKey, make([]byte, 16), WithPadder(padder))
+				if err != nil {
+					t.Fatalf("%T %d-bit len %d: NewAESCBCDecryptor: %v", padder, bits, n, err)
+				}
+
+				var plainOut bytes.Buffer
+				if _, err := dec.Copy(&plainOut, bytes.NewReader(ciphertext.Bytes())); err != nil {
+					t.Fatalf("%T %d-bit len %d: decrypt Copy: %v", padder, bits, n, err)
+				}
+
+				if !bytes.Equal(plainOut.Bytes(), plaintext) {
+					t.Fatalf("%T %d-bit len %d: roundtrip mismatch: got %x want %x", padder, bits, n, plainOut.Bytes(), plaintext)
+				}
+			}
+		}
+	}
+}
+
+func TestAESCFBRoundtrip(t *testing.T) {
+	for _, n := range plaintextLengths {
+		plaintext := testPlaintext(n)
+
+		enc, err := NewAESCFBEncryptor()
+		if err != nil {
+			t.Fatalf("len %d: NewAESCFBEncryptor: %v", n, err)
+		}
+
+		var ciphertext bytes.Buffer
+		if _, err := enc.Copy(&ciphertext, bytes.NewReader(plaintext)); err != nil {
+			t.Fatalf("len %d: encrypt Copy: %v", n, err)
+		}
+
+		dec, err := NewAESCFBDecryptor(enc.AESKey, make([]byte, 16))
+		if err != nil {
+			t.Fatalf("len %d: NewAESCFBDecryptor: %v", n, err)
+		}
+
+		var plainOut bytes.Buffer
+		if _, err := dec.Copy(&plainOut, bytes.NewReader(ciphertext.Bytes())); err != nil {
+			t.Fatalf("len %d: decrypt Copy: %v", n, err)
+		}
+
+		if !bytes.Equal(plainOut.Bytes(), plaintext) {
+			t.Fatalf("len %d: roundtrip mismatch: got %x want %x", n, plainOut.Bytes(), plaintext)
+		}
+	}
+}
+
+func TestAESOFBRoundtrip(t *testing.T) {
+	for _, n := range plaintextLengths {
+		plaintext := testPlaintext(n)
+
+		enc, err := NewAESOFBEncryptor()
+		if err != nil {
+			t.Fatalf("len %d: NewAESOFBEncryptor: %v", n, err)
+		}
+
+		var ciphertext bytes.Buffer
+		if _, err := enc.Copy(&ciphertext, bytes.NewReader(plaintext)); err != nil {
+			t.Fatalf("len %d: encrypt Copy: %v", n, err)
+		}
+
+		dec, err := NewAESOFBDecryptor(enc.AESKey, make([]byte, 16))
+		if err != nil {
+			t.Fatalf("len %d: NewAESOFBDecryptor: %v", n, err)
+		}
+
+		var plainOut bytes.Buffer
+		if _, err := dec.Copy(&plainOut, bytes.NewReader(ciphertext.Bytes())); err != nil {
+			t.Fatalf("len %d: decrypt Copy: %v", n, err)
+		}
+
+		if !bytes.Equal(plainOut.Bytes(), plaintext) {
+			t.Fatalf("len %d: roundtrip mismatch: got %x want %x", n, plainOut.Bytes(), plaintext)
+		}
+	}
+}
+
+func TestAESCTRRoundtrip(t *testing.T) {
+	for _, n := range plaintextLengths {
+		plaintext := testPlaintext(n)
+
+		enc, err := NewAESCTREncryptor()
+		if err != nil {
+			t.Fatalf("len %d: NewAESCTREncryptor: %v", n, err)
+		}
+
+		var ciphertext bytes.Buffer
+		if _, err := enc.Copy(&ciphertext, bytes.NewReader(plaintext)); err != nil {
+			t.Fatalf("len %d: encrypt Copy: %v", n, err)
+		}
+
+		dec, err := NewAESCTRDecryptor(enc.AESKey, make([]byte, 16))
+		if err != nil {
+			t.Fatalf("len %d: NewAESCTRDecryptor: %v", n, err)
+		}
+
+		var plainOut bytes.Buffer
+		if _, err := dec.Copy(&plainOut, bytes.NewReader(ciphertext.Bytes())); err != nil {
+			t.Fatalf("len %d: decrypt Copy: %v", n, err)
+		}
+
+		if !bytes.Equal(plainOut.Bytes(), plaintext) {
+			t.Fatalf("len %d: roundtrip mismatch: got %x want %x", n, plainOut.Bytes(), plaintext)
+		}
+	}
+}
+
+// TestStreamModeKeySizes exercises NewAESCFBEncryptorWithKeySize,
+// NewAESOFBEncryptorWithKeySize, and NewAESCTREncryptorWithKeySize across all
+// three supported key sizes.
+func TestStreamModeKeySizes(t *testing.T) {
+	keySizes := []int{128, 192, 256}
+	plaintext := testPlaintext(37)
+
+	for _, bits := range keySizes {
+		enc, err := NewAESCFBEncryptorWithKeySize(bits)
+		if err != nil {
+			t.Fatalf("CFB %d-bit: NewAESCFBEncryptorWithKeySize: %v", bits, err)
+		}
+		if len(enc.AESKey) != bits/8 {
+			t.Fatalf("CFB %d-bit: AESKey is %d bytes", bits, len(enc.AESKey))
+		}
+		var ciphertext bytes.Buffer
+		if _, err := enc.Copy(&ciphertext, bytes.NewReader(plaintext)); err != nil {
+			t.Fatalf("CFB %d-bit: encrypt Copy: %v", bits, err)
+		}
+		dec, err := NewAESCFBDecryptor(enc.AESKey, make([]byte, 16))
+		if err != nil {
+			t.Fatalf("CFB %d-bit: NewAESCFBDecryptor: %v", bits, err)
+		}
+		var plainOut bytes.Buffer
+		if _, err := dec.Copy(&plainOut, bytes.NewReader(ciphertext.Bytes())); err != nil {
+			t.Fatalf("CFB %d-bit: decrypt Copy: %v", bits, err)
+		}
+		if !bytes.Equal(plainOut.Bytes(), plaintext) {
+			t.Fatalf("CFB %d-bit: roundtrip mismatch: got %x want %x", bits, plainOut.Bytes(), plaintext)
+		}
+	}
+
+	for _, bits := range keySizes {
+		enc, err := NewAESOFBEncryptorWithKeySize(bits)
+		if err != nil {
+			t.Fatalf("OFB %d-bit: NewAESOFBEncryptorWithKeySize: %v", bits, err)
+		}
+		if len(enc.AESKey) != bits/8 {
+			t.Fatalf("OFB %d-bit: AESKey is %d bytes", bits, len(enc.AESKey))
+		}
+		var ciphertext bytes.Buffer
+		if _, err := enc.Copy(&ciphertext, bytes.NewReader(plaintext)); err != nil {
+			t.Fatalf("OFB %d-bit: encrypt Copy: %v", bits, err)
+		}
+		dec, err := NewAESOFBDecryptor(enc.AESKey, make([]byte, 16))
+		if err != nil {
+			t.Fatalf("OFB %d-bit: NewAESOFBDecryptor: %v", bits, err)
+		}
+		var plainOut bytes.Buffer
+		if _, err := dec.Copy(&plainOut, bytes.NewReader(ciphertext.Bytes())); err != nil {
+			t.Fatalf("OFB %d-bit: decrypt Copy: %v", bits, err)
+		}
+		if !bytes.Equal(plainOut.Bytes(), plaintext) {
+			t.Fatalf("OFB %d-bit: roundtrip mismatch: got %x want %x", bits, plainOut.Bytes(), plaintext)
+		}
+	}
+
+	for _, bits := range keySizes {
+		enc, err := NewAESCTREncryptorWithKeySize(bits)
+		if err != nil {
+			t.Fatalf("CTR %d-bit: NewAESCTREncryptorWithKeySize: %v", bits, err)
+		}
+		if len(enc.AESKey) != bits/8 {
+			t.Fatalf("CTR %d-bit: AESKey is %d bytes", bits, len(enc.AESKey))
+		}
+		var ciphertext bytes.Buffer
+		if _, err := enc.Copy(&ciphertext, bytes.NewReader(plaintext)); err != nil {
+			t.Fatalf("CTR %d-bit: encrypt Copy: %v", bits, err)
+		}
+		dec, err := NewAESCTRDecryptor(enc.AESKey, make([]byte, 16))
+		if err != nil {
+			t.Fatalf("CTR %d-bit: NewAESCTRDecryptor: %v", bits, err)
+		}
+		var plainOut bytes.Buffer
+		if _, err := dec.Copy(&plainOut, bytes.NewReader(ciphertext.Bytes())); err != nil {
+			t.Fatalf("CTR %d-bit: decrypt Copy: %v", bits, err)
+		}
+		if !bytes.Equal(plainOut.Bytes(), plaintext) {
+			t.Fatalf("CTR %d-bit: roundtrip mismatch: got %x want %x", bits, plainOut.Bytes(), plaintext)
+		}
+	}
+
+	if _, err := NewAESCFBEncryptorWithKeySize(100); err == nil {
+		t.Fatal("expected an error for an invalid key size")
+	}
+}
+
+func TestAESCBCHMACRoundtrip(t *testing.T) {
+	for _, n := range plaintextLengths {
+		plaintext := testPlaintext(n)
+
+		enc, err := NewAESCBCHMACEncryptor()
+		if err != nil {
+			t.Fatalf("len %d: NewAESCBCHMACEncryptor: %v", n, err)
+		}
+
+		var ciphertext bytes.Buffer
+		if _, err := enc.Copy(&ciphertext, bytes.NewReader(plaintext)); err != nil {
+			t.Fatalf("len %d: encrypt Copy: %v", n, err)
+		}
+
+		dec, err := NewAESCBCHMACDecryptor(enc.MasterSecret, make([]byte, 16))
+		if err != nil {
+			t.Fatalf("len %d: NewAESCBCHMACDecryptor: %v", n, err)
+		}
+
+		var plainOut bytes.Buffer
+		if _, err := dec.Copy(&plainOut, bytes.NewReader(ciphertext.Bytes())); err != nil {
+			t.Fatalf("len %d: decrypt Copy: %v", n, err)
+		}
+
+		if !bytes.Equal(plainOut.Bytes(), plaintext) {
+			t.Fatalf("len %d: roundtrip mismatch: got %x want %x", n, plainOut.Bytes(), plaintext)
+		}
+	}
+}
+
+// TestAESCBCHMACTamperDetection confirms that flipping a ciphertext byte is
+// caught by Close() rather than silently decrypted.
+func TestAESCBCHMACTamperDetection(t *testing.T) {
+	enc, err := NewAESCBCHMACEncryptor()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ciphertext bytes.Buffer
+	if _, err := enc.Copy(&ciphertext, bytes.NewReader(testPlaintext(61))); err != nil {
+		t.Fatalf("encrypt Copy: %v", err)
+	}
+
+	tampered := append([]byte(nil), ciphertext.Bytes()...)
+	tampered[len(tampered)/2] ^= 0xFF
+
+	dec, err := NewAESCBCHMACDecryptor(enc.MasterSecret, make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var discard bytes.Buffer
+	if _, err := dec.Copy(&discard, bytes.NewReader(tampered)); err == nil {
+		t.Fatal("expected an authentication failure for tampered ciphertext, got nil")
+	}
+}
+
+// openSSLKDFCases covers both key-derivation paths NewOpenSSLEncryptor and
+// NewOpenSSLDecryptor support: the default PBKDF2-HMAC-SHA256 (at both its
+// default and an overridden iteration count) and the legacy MD5-based
+// EVP_BytesToKey used by `openssl enc` before PBKDF2 became the default.
+var openSSLKDFCases = []struct {
+	name string
+	opts []OpenSSLOption
+}{
+	{"default PBKDF2", nil},
+	{"PBKDF2 with custom iterations", []OpenSSLOption{WithIterations(1000)}},
+	{"legacy EVP_BytesToKey", []OpenSSLOption{WithEVPBytesToKey()}},
+}
+
+func TestOpenSSLRoundtrip(t *testing.T) {
+	for _, kc := range openSSLKDFCases {
+		for _, n := range plaintextLengths {
+			plaintext := testPlaintext(n)
+
+			enc, err := NewOpenSSLEncryptor("correct horse battery staple", kc.opts...)
+			if err != nil {
+				t.Fatalf("%s len %d: NewOpenSSLEncryptor: %v", kc.name, n, err)
+			}
+
+			var ciphertext bytes.Buffer
+			if _, err := enc.Copy(&ciphertext, bytes.NewReader(plaintext)); err != nil {
+				t.Fatalf("%s len %d: encrypt Copy: %v", kc.name, n, err)
+			}
+
+			dec, err := NewOpenSSLDecryptor("correct horse battery staple", kc.opts...)
+			if err != nil {
+				t.Fatalf("%s len %d: NewOpenSSLDecryptor: %v", kc.name, n, err)
+			}
+
+			var plainOut bytes.Buffer
+			if _, err := dec.Copy(&plainOut, bytes.NewReader(ciphertext.Bytes())); err != nil {
+				t.Fatalf("%s len %d: decrypt Copy: %v", kc.name, n, err)
+			}
+
+			if !bytes.Equal(plainOut.Bytes(), plaintext) {
+				t.Fatalf("%s len %d: roundtrip mismatch: got %x want %x", kc.name, n, plainOut.Bytes(), plaintext)
+			}
+		}
+	}
+}
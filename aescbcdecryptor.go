@@ -26,18 +26,29 @@ type AESCBCDecryptor struct {
 	cipher         cipher.Block
 	cbc            cipher.BlockMode
 	isClosed       bool
+	padder         Padder
 }
 
 // NewDecrypytor returns a Decryptor instance with properly initialized member variables.
 // The AESKey and IV must be supplied and must be the same key and IV
 // used to encrypt the data.
-
-func NewAESCBCDecryptor(aesKey []byte, iv []byte) (*AESCBCDecryptor, error) {
+//
+// By default, PKCS7 padding is assumed. Pass WithPadder() if the ciphertext
+// was padded with a different scheme.
+func NewAESCBCDecryptor(aesKey []byte, iv []byte, opts ...Option) (*AESCBCDecryptor, error) {
 	var err error
 	var e AESCBCDecryptor
 
-	if len(aesKey) != 32 {
-		return nil, errors.New("aes key must be 32 bytes long")
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	e.padder = o.padder
+
+	switch len(aesKey) {
+	case 16, 24, 32:
+	default:
+		return nil, errors.New("aes key must be 16, 24, or 32 bytes long")
 	}
 	e.aesKey = aesKey
 
@@ -90,6 +101,12 @@ func (e *AESCBCDecryptor) Write(p []byte) (n int, err error) {
 		return 0, errors.New("writer has been closed")
 	}
 
+	//All of p is consumed one way or another below, either decrypted or
+	//held back in inputoverflow, so the original length is what we report
+	//back to the caller - not the length of the block-aligned slice we end
+	//up decrypting.
+	consumed := len(p)
+
 	//If there's any existing input overflow, that must be
 	//prepended to the incoming data
 	if len(e.inputoverflow) > 0 {
@@ -118,14 +135,14 @@ func (e *AESCBCDecryptor) Write(p []byte) (n int, err error) {
 	e.cbc.CryptBlocks(plaintext, p)
 
 	e.outputoverflow = append(e.outputoverflow, plaintext...)
-	return len(p), nil
+	return consumed, nil
 }
 
 // Read implements io.Reader and returns plaintext that has been decrypted.
 // Data is available on Read() after ciphertext has been written to Write().
 //
 // The last 16 bytes of plaintext data are retainted internally until Close()
-// is called, so that Close() can strip off the PKCS7 padding. The remaining
+// is called, so that Close() can strip off the padding. The remaining
 // plaintext becomes available to Read() after Close() is called. No more data
 // may be written after Close() is called.
 //
@@ -158,17 +175,18 @@ func (e *AESCBCDecryptor) Read(p []byte) (n int, err error) {
 }
 
 // Close is used to signal no more data will be written to the Decryptor. After
-// Close() is called, PKCS7 padding is removed from the buffered plaintext
-// so that it will not be returned to the caller.
+// Close() is called, padding is removed from the buffered plaintext (using
+// the configured Padder, PKCS7 by default) so that it will not be returned
+// to the caller.
 //
 // At least one more call to Read() must be performed after calling Close() to
 // ensure all plaintext has been read.
 func (e *AESCBCDecryptor) Close() error {
 	if len(e.outputoverflow) < 16 {
-		return errors.New("not enough bytes in read buffer to strip PKCS7 padding")
+		return errors.New("not enough bytes in read buffer to remove padding")
 	}
 
-	p, err := Pkcs7Unpad(e.outputoverflow)
+	p, err := e.padder.Unpad(e.outputoverflow)
 	if err != nil {
 		return err
 	}
@@ -178,66 +196,62 @@ func (e *AESCBCDecryptor) Close() error {
 	return nil
 }
 
-// Copy encrypts the data read from the io.Reader and writes it
-// to io.Writer. As part of the encryption process, the AES initialization vector
-// (IV) is prepended to the ciphertext so that it can be recovered from the data
-// stream by the decryptor.
+// Copy decrypts the data read from the io.Reader and writes it
+// to io.Writer. The first 16 bytes read from src are consumed as the AES
+// initialization vector (IV) that was prepended to the ciphertext by the
+// encryptor.
 //
-// When using Copy(), the IV in the AESCBCDecryptor is overwritten with the first
-// 16 bytes of the source Reader.
+// When using Copy(), the IV in the AESCBCDecryptor is overwritten with the
+// first 16 bytes read from src (via io.ReadFull, so a short read is
+// reported as an error rather than silently producing a wrong IV), and the
+// internal cipher.BlockMode is re-initialized with it.
 //
-// The read buffer size is taken from the CopyReadBufferSizeHint member variable
-func (e *AESCBCDecryptor) Copy(dst io.Writer, src io.Reader) (read int64, err error) {
-	shouldClose := false
-	read = int64(0)
-	inputBuf := make([]byte, e.CopyBufferSize)
+// written reports the number of bytes actually written to dst (the
+// plaintext), not the number of bytes read from src.
+//
+// The read buffer size is taken from the CopyBufferSize member variable.
+func (e *AESCBCDecryptor) Copy(dst io.Writer, src io.Reader) (written int64, err error) {
+	//Read the IV from the input stream in full; a short read here would
+	//otherwise silently produce a wrong IV and garbage plaintext.
+	if _, err := io.ReadFull(src, e.iv); err != nil {
+		return 0, err
+	}
+	e.cbc = cipher.NewCBCDecrypter(e.cipher, e.iv)
 
-	//Read the IV from the output stream
-	src.Read(e.iv)
-	read += int64(len(e.iv))
+	inputBuf := make([]byte, e.CopyBufferSize)
+	outputBuf := make([]byte, e.CopyBufferSize)
 
 	//Read until we get an EOF
 	for {
-		//Read from the source file
-		n, err := src.Read(inputBuf)
-		if err == io.EOF {
-			shouldClose = true
-		} else {
-			if err != nil {
-				return read, err
+		n, rerr := src.Read(inputBuf)
+		if n > 0 {
+			//Write ciphertext to the decryptor, honoring any partial write
+			if werr := writeAll(e.Write, inputBuf[:n]); werr != nil {
+				return written, werr
 			}
-		}
 
-		//Write cipertext to the decryptor
-		e.Write(inputBuf[:n])
-
-		//Read plaintext from the decryptor
-		e.Read(inputBuf)
-
-		//Write plaintext to the destination file
-		n, err = dst.Write(inputBuf[:n])
-		if err != nil {
-			return read, err
-		}
-		read += int64(n)
-
-		if shouldClose {
-			e.Close()
-			//Read any remaining ciphertext
-			for {
-				n, _ = e.Read(inputBuf)
-				if n == 0 {
-					break
-				}
-				read += int64(n)
-
-				_, err = dst.Write(inputBuf[:n])
-				if err != nil {
-					return read, err
-				}
+			//Drain whatever plaintext that produced to dst, using a
+			//separate buffer so ciphertext-in and plaintext-out are never
+			//aliased
+			if derr := drainAvailable(dst, e.Read, outputBuf, &written); derr != nil {
+				return written, derr
 			}
+		}
+
+		if rerr == io.EOF {
 			break
 		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		return written, err
 	}
-	return read, nil
+	if err := drainAvailable(dst, e.Read, outputBuf, &written); err != nil {
+		return written, err
+	}
+
+	return written, nil
 }
@@ -31,6 +31,7 @@ type AESCBCEncryptor struct {
 	cipher         cipher.Block
 	cbc            cipher.BlockMode
 	isClosed       bool
+	padder         Padder
 }
 
 // NewEncrypytor returns an Encryptor instance with properly initialized member variables.
@@ -43,24 +44,68 @@ type AESCBCEncryptor struct {
 // There is no functionality to manually set the AESKey or the IV. Writing to these
 // values after instantiation will not change the state of the cipher or block
 // mode.
-func NewAESCBCEncryptor() (*AESCBCEncryptor, error) {
-	var err error
-	var e AESCBCEncryptor
+//
+// By default, PKCS7 padding is used. Pass WithPadder() to use a different
+// padding scheme.
+//
+// The generated AESKey is 32 bytes (AES-256). Use
+// NewAESCBCEncryptorWithKeySize() to select AES-128 or AES-192 instead.
+func NewAESCBCEncryptor(opts ...Option) (*AESCBCEncryptor, error) {
+	return newAESCBCEncryptor(32, opts...)
+}
+
+// NewAESCBCEncryptorWithKeySize behaves like NewAESCBCEncryptor, but generates
+// an AESKey of the requested size. bits must be 128, 192, or 256.
+func NewAESCBCEncryptorWithKeySize(bits int, opts ...Option) (*AESCBCEncryptor, error) {
+	switch bits {
+	case 128, 192, 256:
+	default:
+		return nil, errors.New("key size must be 128, 192, or 256 bits")
+	}
+	return newAESCBCEncryptor(bits/8, opts...)
+}
 
+func newAESCBCEncryptor(keySize int, opts ...Option) (*AESCBCEncryptor, error) {
 	//generate a random AES key
-	e.AESKey = make([]byte, 32)
-	_, err = rand.Read(e.AESKey)
-	if err != nil {
+	aesKey := make([]byte, keySize)
+	if _, err := rand.Read(aesKey); err != nil {
 		return nil, err
 	}
 
+	return newAESCBCEncryptorWithKey(aesKey, opts...)
+}
+
+// newAESCBCEncryptorWithKey is like newAESCBCEncryptor, but uses the
+// supplied AESKey instead of generating one. It exists so that callers
+// elsewhere in the package (such as AESCBCHMACEncryptor) that derive their
+// own AES key can still reuse the CBC plumbing here.
+func newAESCBCEncryptorWithKey(aesKey []byte, opts ...Option) (*AESCBCEncryptor, error) {
 	//generate a random initialization vector
-	e.IV = make([]byte, 16)
-	_, err = rand.Read(e.IV)
-	if err != nil {
+	iv := make([]byte, 16)
+	if _, err := rand.Read(iv); err != nil {
 		return nil, err
 	}
 
+	return newAESCBCEncryptorWithKeyAndIV(aesKey, iv, opts...)
+}
+
+// newAESCBCEncryptorWithKeyAndIV is like newAESCBCEncryptorWithKey, but uses
+// the supplied IV instead of generating one. It exists so that callers
+// elsewhere in the package (such as OpenSSLEncryptor) that derive their own
+// key and IV from a passphrase can still reuse the CBC plumbing here.
+func newAESCBCEncryptorWithKeyAndIV(aesKey []byte, iv []byte, opts ...Option) (*AESCBCEncryptor, error) {
+	var err error
+	var e AESCBCEncryptor
+
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	e.padder = o.padder
+
+	e.AESKey = aesKey
+	e.IV = iv
+
 	//input overflow is used when Write() gives us a partial
 	//AES block. The remaining bytes, to be used in the next
 	//block, are stored here
@@ -101,6 +146,12 @@ func (e *AESCBCEncryptor) Write(p []byte) (n int, err error) {
 		return 0, errors.New("writer has been closed")
 	}
 
+	//All of p is consumed one way or another below, either encrypted or
+	//held back in inputoverflow, so the original length is what we report
+	//back to the caller - not the length of the block-aligned slice we end
+	//up encrypting.
+	consumed := len(p)
+
 	//If there's any existing input overflow, that must be
 	//prepended to the incoming data
 	if len(e.inputoverflow) > 0 {
@@ -135,7 +186,7 @@ func (e *AESCBCEncryptor) Write(p []byte) (n int, err error) {
 
 	//append the new ciphertext to the output waiting to be read
 	e.outputoverflow = append(e.outputoverflow, cipherText...)
-	return len(p), nil
+	return consumed, nil
 }
 
 // Read implements io.Reader and returns ciphertext that has been encrypted.
@@ -165,15 +216,15 @@ func (e *AESCBCEncryptor) Read(p []byte) (n int, err error) {
 }
 
 // Close is used to signal no more data will be written to the Encryptor. After
-// Close() is called, PKCS7 padding is added to the plaintext to aid in proper
-// decryption and the padded data is added to the ciphertext that is available
-// on the Read() method.
+// Close() is called, padding is added to the plaintext (using the configured
+// Padder, PKCS7 by default) to aid in proper decryption, and the padded data
+// is added to the ciphertext that is available on the Read() method.
 //
 // At least one more call to Read() mustbe peformed after calling Close() to
 // ensure all ciphertext has been read.
 func (e *AESCBCEncryptor) Close() {
 	//We need to pad the last block and encrypt it
-	p := Pkcs7Pad(e.inputoverflow, e.cbc.BlockSize())
+	p := e.padder.Pad(e.inputoverflow, e.cbc.BlockSize())
 	lastBlock := make([]byte, len(p))
 	e.cbc.CryptBlocks(lastBlock, p)
 	e.outputoverflow = append(e.outputoverflow, lastBlock...)
@@ -185,57 +236,46 @@ func (e *AESCBCEncryptor) Close() {
 // (IV) is prepended to the ciphertext so that it can be recovered from the data
 // stream by the decryptor.
 //
-// The read buffer size is taken from the CopyReadBufferSizeHint member variable
+// written reports the number of bytes actually written to dst (the IV plus
+// the ciphertext), not the number of bytes read from src.
+//
+// The read buffer size is taken from the CopyBufferSize member variable.
 func (e *AESCBCEncryptor) Copy(dst io.Writer, src io.Reader) (written int64, err error) {
-	shouldClose := false
-	written = int64(0)
 	inputBuf := make([]byte, e.CopyBufferSize)
+	outputBuf := make([]byte, e.CopyBufferSize)
 
 	//Write the IV to the output stream
-	dst.Write(e.IV)
-	written += int64(len(e.IV))
+	if err := writeFull(dst, e.IV, &written); err != nil {
+		return written, err
+	}
 
 	//Read until we get an EOF
 	for {
-		//Read from the source file
-		n, err := src.Read(inputBuf)
-		if err == io.EOF {
-			shouldClose = true
-		} else {
-			if err != nil {
-				return written, err
+		n, rerr := src.Read(inputBuf)
+		if n > 0 {
+			//Write plaintext to the encryptor, honoring any partial write
+			if werr := writeAll(e.Write, inputBuf[:n]); werr != nil {
+				return written, werr
 			}
-		}
 
-		//Write plaintext to the encryptor
-		e.Write(inputBuf[:n])
-
-		//Read ciphertext from the encryptor
-		n, _ = e.Read(inputBuf)
-
-		//Write ciphertext to the destination file
-		n, err = dst.Write(inputBuf[:n])
-		written += int64(n)
-		if err != nil {
-			return written, err
+			//Drain whatever ciphertext that produced to dst
+			if derr := drainAvailable(dst, e.Read, outputBuf, &written); derr != nil {
+				return written, derr
+			}
 		}
 
-		if shouldClose {
-			e.Close()
-			//Read any remaining ciphertext
-			for {
-				n, _ = e.Read(inputBuf)
-				if n == 0 {
-					break
-				}
-				n, err = dst.Write(inputBuf[:n])
-				if err != nil {
-					return written, err
-				}
-				written += int64(n)
-			}
+		if rerr == io.EOF {
 			break
 		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+
+	e.Close()
+	if err := drainAvailable(dst, e.Read, outputBuf, &written); err != nil {
+		return written, err
 	}
+
 	return written, nil
 }
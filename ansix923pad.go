@@ -0,0 +1,42 @@
+package aescbc
+
+import "errors"
+
+// AnsiX923Padder implements Padder using the ANSI X.923 padding scheme:
+// zero bytes followed by a single byte giving the padding length.
+type AnsiX923Padder struct{}
+
+// Pad implements Padder.
+func (AnsiX923Padder) Pad(block []byte, blockSize int) []byte {
+	r := len(block) % blockSize
+	pl := blockSize - r
+
+	padded := make([]byte, len(block), len(block)+pl)
+	copy(padded, block)
+
+	for i := 1; i < pl; i++ {
+		padded = append(padded, 0x00)
+	}
+	padded = append(padded, byte(pl))
+	return padded
+}
+
+// Unpad implements Padder.
+func (AnsiX923Padder) Unpad(block []byte) ([]byte, error) {
+	if len(block) == 0 {
+		return nil, nil
+	}
+
+	pl := int(block[len(block)-1])
+	if pl == 0 || pl > len(block) {
+		return nil, errors.New("invalid padding")
+	}
+
+	for _, b := range block[len(block)-pl : len(block)-1] {
+		if b != 0x00 {
+			return nil, errors.New("invalid padding")
+		}
+	}
+
+	return block[:len(block)-pl], nil
+}
@@ -0,0 +1,256 @@
+package aescbc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// The AESCFBEncryptor type is used to encrypt plaintext data to AES-CFB
+// encrypted ciphertext. Unlike AESCBCEncryptor, CFB is a stream mode: it has
+// no block-alignment requirement, so Close() does not need to pad anything.
+//
+// This type should be created by NewAESCFBEncryptor() rather than by
+// directly instantiating the type in your code.
+//
+// Exported fields:
+// IV: the initialization vector used to initialize the AES cipher
+// AESKey: the AES key used to initialize the AES cipher
+// CopyBufferSize: the size (in bytes) of the read/write buffer that Copy() will use
+type AESCFBEncryptor struct {
+	streamBase
+	IV     []byte
+	AESKey []byte
+}
+
+// NewAESCFBEncryptor returns an AESCFBEncryptor instance with properly
+// initialized member variables. The AESKey and IV are populated from
+// crypto/rand and the internal AES cipher and CFB stream are properly
+// initialized.
+//
+// After calling NewAESCFBEncryptor() the calling application should copy the
+// AESKey and the IV so that they can be provided to the decrypting
+// application.
+//
+// The generated AESKey is 32 bytes (AES-256). Use
+// NewAESCFBEncryptorWithKeySize() to select AES-128 or AES-192 instead.
+func NewAESCFBEncryptor() (*AESCFBEncryptor, error) {
+	return newAESCFBEncryptor(32)
+}
+
+// NewAESCFBEncryptorWithKeySize behaves like NewAESCFBEncryptor, but
+// generates an AESKey of the requested size. bits must be 128, 192, or 256.
+func NewAESCFBEncryptorWithKeySize(bits int) (*AESCFBEncryptor, error) {
+	switch bits {
+	case 128, 192, 256:
+	default:
+		return nil, errors.New("key size must be 128, 192, or 256 bits")
+	}
+	return newAESCFBEncryptor(bits / 8)
+}
+
+func newAESCFBEncryptor(keySize int) (*AESCFBEncryptor, error) {
+	var e AESCFBEncryptor
+
+	//generate a random AES key
+	e.AESKey = make([]byte, keySize)
+	if _, err := rand.Read(e.AESKey); err != nil {
+		return nil, err
+	}
+
+	//generate a random initialization vector
+	e.IV = make([]byte, 16)
+	if _, err := rand.Read(e.IV); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(e.AESKey)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := cipher.NewCFBEncrypter(block, e.IV)
+	e.streamBase = newStreamBase(stream.XORKeyStream)
+
+	return &e, nil
+}
+
+// Write implements io.Writer and accepts plaintext to be encrypted. After
+// writing plaintext to Write(), ciphertext will become available on the
+// Read() method.
+func (e *AESCFBEncryptor) Write(p []byte) (n int, err error) {
+	return e.write(p)
+}
+
+// Read implements io.Reader and returns ciphertext that has been encrypted.
+// If no ciphertext is available, a zero-byte slice is returned and error is
+// nil. Error will return io.EOF after the Close() method has been called and
+// no more data is available to read.
+func (e *AESCFBEncryptor) Read(p []byte) (n int, err error) {
+	return e.read(p)
+}
+
+// Close is used to signal no more data will be written to the Encryptor.
+// Unlike AESCBCEncryptor.Close(), there is no padding to add: CFB has no
+// block-alignment requirement, so any remaining ciphertext is already
+// available on Read().
+func (e *AESCFBEncryptor) Close() {
+	e.close()
+}
+
+// Copy encrypts the data read from the io.Reader and writes it to the
+// io.Writer. As part of the encryption process, the AES initialization
+// vector (IV) is prepended to the ciphertext so that it can be recovered
+// from the data stream by the decryptor.
+//
+// written reports the number of bytes actually written to dst (the IV plus
+// the ciphertext), not the number of bytes read from src.
+//
+// The read buffer size is taken from the CopyBufferSize member variable.
+func (e *AESCFBEncryptor) Copy(dst io.Writer, src io.Reader) (written int64, err error) {
+	inputBuf := make([]byte, e.CopyBufferSize)
+	outputBuf := make([]byte, e.CopyBufferSize)
+
+	//Write the IV to the output stream
+	if err := writeFull(dst, e.IV, &written); err != nil {
+		return written, err
+	}
+
+	for {
+		n, rerr := src.Read(inputBuf)
+		if n > 0 {
+			if werr := writeAll(e.Write, inputBuf[:n]); werr != nil {
+				return written, werr
+			}
+			if derr := drainAvailable(dst, e.Read, outputBuf, &written); derr != nil {
+				return written, derr
+			}
+		}
+
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+
+	e.Close()
+	if err := drainAvailable(dst, e.Read, outputBuf, &written); err != nil {
+		return written, err
+	}
+
+	return written, nil
+}
+
+// The AESCFBDecryptor type is used to decrypt AES-CFB ciphertext to
+// plaintext.
+//
+// This type should be created by NewAESCFBDecryptor() rather than by
+// directly instantiating the type in your code.
+type AESCFBDecryptor struct {
+	streamBase
+	iv     []byte
+	aesKey []byte
+	block  cipher.Block
+}
+
+// NewAESCFBDecryptor returns an AESCFBDecryptor instance with properly
+// initialized member variables. The aesKey and iv must be supplied and must
+// be the same key and IV used to encrypt the data.
+func NewAESCFBDecryptor(aesKey []byte, iv []byte) (*AESCFBDecryptor, error) {
+	var e AESCFBDecryptor
+
+	switch len(aesKey) {
+	case 16, 24, 32:
+	default:
+		return nil, errors.New("aes key must be 16, 24, or 32 bytes long")
+	}
+	e.aesKey = aesKey
+
+	if len(iv) != 16 {
+		return nil, errors.New("IV must be 16 bytes long")
+	}
+	e.iv = iv
+
+	block, err := aes.NewCipher(e.aesKey)
+	if err != nil {
+		return nil, err
+	}
+	e.block = block
+
+	stream := cipher.NewCFBDecrypter(block, e.iv)
+	e.streamBase = newStreamBase(stream.XORKeyStream)
+
+	return &e, nil
+}
+
+// Write implements io.Writer and accepts ciphertext to be decrypted. After
+// writing ciphertext to Write(), plaintext will become available on the
+// Read() method.
+func (e *AESCFBDecryptor) Write(p []byte) (n int, err error) {
+	return e.write(p)
+}
+
+// Read implements io.Reader and returns plaintext that has been decrypted.
+func (e *AESCFBDecryptor) Read(p []byte) (n int, err error) {
+	return e.read(p)
+}
+
+// Close is used to signal no more data will be written to the Decryptor.
+// Unlike AESCBCDecryptor.Close(), there is no padding to remove.
+func (e *AESCFBDecryptor) Close() error {
+	e.close()
+	return nil
+}
+
+// Copy decrypts the data read from the io.Reader and writes it to the
+// io.Writer. The first 16 bytes read from src are consumed as the IV.
+//
+// When using Copy(), the IV in the AESCFBDecryptor is overwritten with the
+// first 16 bytes read from src (via io.ReadFull, so a short read is
+// reported as an error rather than silently producing a wrong IV), and the
+// internal cipher.Stream is re-initialized with it.
+//
+// written reports the number of bytes actually written to dst (the
+// plaintext), not the number of bytes read from src.
+//
+// The read buffer size is taken from the CopyBufferSize member variable.
+func (e *AESCFBDecryptor) Copy(dst io.Writer, src io.Reader) (written int64, err error) {
+	if _, err := io.ReadFull(src, e.iv); err != nil {
+		return 0, err
+	}
+	stream := cipher.NewCFBDecrypter(e.block, e.iv)
+	e.process = stream.XORKeyStream
+
+	inputBuf := make([]byte, e.CopyBufferSize)
+	outputBuf := make([]byte, e.CopyBufferSize)
+
+	for {
+		n, rerr := src.Read(inputBuf)
+		if n > 0 {
+			if werr := writeAll(e.Write, inputBuf[:n]); werr != nil {
+				return written, werr
+			}
+			if derr := drainAvailable(dst, e.Read, outputBuf, &written); derr != nil {
+				return written, derr
+			}
+		}
+
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+
+	e.Close()
+	if err := drainAvailable(dst, e.Read, outputBuf, &written); err != nil {
+		return written, err
+	}
+
+	return written, nil
+}
@@ -0,0 +1,65 @@
+package aescbc
+
+import (
+	"errors"
+	"io"
+)
+
+// writeFull writes all of p to dst, looping over partial writes and
+// accumulating the number of bytes actually written into *written. It is
+// the dst-side counterpart to io.ReadFull.
+func writeFull(dst io.Writer, p []byte, written *int64) error {
+	off := 0
+	for off < len(p) {
+		n, err := dst.Write(p[off:])
+		off += n
+		*written += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAll feeds p to write (typically an Encryptor's or Decryptor's
+// Write method) in full, looping over any partial writes instead of
+// assuming the first call consumes everything.
+func writeAll(write func([]byte) (int, error), p []byte) error {
+	off := 0
+	for off < len(p) {
+		n, err := write(p[off:])
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New("short write")
+		}
+		off += n
+	}
+	return nil
+}
+
+// drainAvailable repeatedly calls read (typically an Encryptor's or
+// Decryptor's Read method) and forwards whatever it returns to dst via
+// writeFull, until read reports io.EOF (the stream has been Close()d and is
+// fully drained) or returns zero bytes with a nil error (nothing more is
+// available right now).
+func drainAvailable(dst io.Writer, read func([]byte) (int, error), buf []byte, written *int64) error {
+	for {
+		n, err := read(buf)
+		if n > 0 {
+			if werr := writeFull(dst, buf[:n], written); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+	}
+}
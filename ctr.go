@@ -0,0 +1,252 @@
+package aescbc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// The AESCTREncryptor type is used to encrypt plaintext data to AES-CTR
+// encrypted ciphertext. Like AESCFBEncryptor, CTR is a stream mode with no
+// block-alignment requirement, so Close() does not need to pad anything.
+//
+// This type should be created by NewAESCTREncryptor() rather than by
+// directly instantiating the type in your code.
+//
+// Exported fields:
+// IV: the initialization vector (used as the initial counter value)
+// AESKey: the AES key used to initialize the AES cipher
+// CopyBufferSize: the size (in bytes) of the read/write buffer that Copy() will use
+type AESCTREncryptor struct {
+	streamBase
+	IV     []byte
+	AESKey []byte
+}
+
+// NewAESCTREncryptor returns an AESCTREncryptor instance with properly
+// initialized member variables. The AESKey and IV are populated from
+// crypto/rand and the internal AES cipher and CTR stream are properly
+// initialized.
+//
+// After calling NewAESCTREncryptor() the calling application should copy the
+// AESKey and the IV so that they can be provided to the decrypting
+// application.
+//
+// The generated AESKey is 32 bytes (AES-256). Use
+// NewAESCTREncryptorWithKeySize() to select AES-128 or AES-192 instead.
+func NewAESCTREncryptor() (*AESCTREncryptor, error) {
+	return newAESCTREncryptor(32)
+}
+
+// NewAESCTREncryptorWithKeySize behaves like NewAESCTREncryptor, but
+// generates an AESKey of the requested size. bits must be 128, 192, or 256.
+func NewAESCTREncryptorWithKeySize(bits int) (*AESCTREncryptor, error) {
+	switch bits {
+	case 128, 192, 256:
+	default:
+		return nil, errors.New("key size must be 128, 192, or 256 bits")
+	}
+	return newAESCTREncryptor(bits / 8)
+}
+
+func newAESCTREncryptor(keySize int) (*AESCTREncryptor, error) {
+	var e AESCTREncryptor
+
+	//generate a random AES key
+	e.AESKey = make([]byte, keySize)
+	if _, err := rand.Read(e.AESKey); err != nil {
+		return nil, err
+	}
+
+	//generate a random initial counter value
+	e.IV = make([]byte, 16)
+	if _, err := rand.Read(e.IV); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(e.AESKey)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := cipher.NewCTR(block, e.IV)
+	e.streamBase = newStreamBase(stream.XORKeyStream)
+
+	return &e, nil
+}
+
+// Write implements io.Writer and accepts plaintext to be encrypted. After
+// writing plaintext to Write(), ciphertext will become available on the
+// Read() method.
+func (e *AESCTREncryptor) Write(p []byte) (n int, err error) {
+	return e.write(p)
+}
+
+// Read implements io.Reader and returns ciphertext that has been encrypted.
+func (e *AESCTREncryptor) Read(p []byte) (n int, err error) {
+	return e.read(p)
+}
+
+// Close is used to signal no more data will be written to the Encryptor.
+// Unlike AESCBCEncryptor.Close(), there is no padding to add.
+func (e *AESCTREncryptor) Close() {
+	e.close()
+}
+
+// Copy encrypts the data read from the io.Reader and writes it to the
+// io.Writer. As part of the encryption process, the AES initialization
+// vector (IV) is prepended to the ciphertext so that it can be recovered
+// from the data stream by the decryptor.
+//
+// written reports the number of bytes actually written to dst (the IV plus
+// the ciphertext), not the number of bytes read from src.
+//
+// The read buffer size is taken from the CopyBufferSize member variable.
+func (e *AESCTREncryptor) Copy(dst io.Writer, src io.Reader) (written int64, err error) {
+	inputBuf := make([]byte, e.CopyBufferSize)
+	outputBuf := make([]byte, e.CopyBufferSize)
+
+	if err := writeFull(dst, e.IV, &written); err != nil {
+		return written, err
+	}
+
+	for {
+		n, rerr := src.Read(inputBuf)
+		if n > 0 {
+			if werr := writeAll(e.Write, inputBuf[:n]); werr != nil {
+				return written, werr
+			}
+			if derr := drainAvailable(dst, e.Read, outputBuf, &written); derr != nil {
+				return written, derr
+			}
+		}
+
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+
+	e.Close()
+	if err := drainAvailable(dst, e.Read, outputBuf, &written); err != nil {
+		return written, err
+	}
+
+	return written, nil
+}
+
+// The AESCTRDecryptor type is used to decrypt AES-CTR ciphertext to
+// plaintext.
+//
+// This type should be created by NewAESCTRDecryptor() rather than by
+// directly instantiating the type in your code.
+type AESCTRDecryptor struct {
+	streamBase
+	iv     []byte
+	aesKey []byte
+	block  cipher.Block
+}
+
+// NewAESCTRDecryptor returns an AESCTRDecryptor instance with properly
+// initialized member variables. The aesKey and iv must be supplied and must
+// be the same key and initial counter value used to encrypt the data.
+func NewAESCTRDecryptor(aesKey []byte, iv []byte) (*AESCTRDecryptor, error) {
+	var e AESCTRDecryptor
+
+	switch len(aesKey) {
+	case 16, 24, 32:
+	default:
+		return nil, errors.New("aes key must be 16, 24, or 32 bytes long")
+	}
+	e.aesKey = aesKey
+
+	if len(iv) != 16 {
+		return nil, errors.New("IV must be 16 bytes long")
+	}
+	e.iv = iv
+
+	block, err := aes.NewCipher(e.aesKey)
+	if err != nil {
+		return nil, err
+	}
+	e.block = block
+
+	//CTR is symmetric: the same keystream is used to encrypt and decrypt
+	stream := cipher.NewCTR(block, e.iv)
+	e.streamBase = newStreamBase(stream.XORKeyStream)
+
+	return &e, nil
+}
+
+// Write implements io.Writer and accepts ciphertext to be decrypted. After
+// writing ciphertext to Write(), plaintext will become available on the
+// Read() method.
+func (e *AESCTRDecryptor) Write(p []byte) (n int, err error) {
+	return e.write(p)
+}
+
+// Read implements io.Reader and returns plaintext that has been decrypted.
+func (e *AESCTRDecryptor) Read(p []byte) (n int, err error) {
+	return e.read(p)
+}
+
+// Close is used to signal no more data will be written to the Decryptor.
+// Unlike AESCBCDecryptor.Close(), there is no padding to remove.
+func (e *AESCTRDecryptor) Close() error {
+	e.close()
+	return nil
+}
+
+// Copy decrypts the data read from the io.Reader and writes it to the
+// io.Writer. The first 16 bytes read from src are consumed as the initial
+// counter value.
+//
+// When using Copy(), the IV in the AESCTRDecryptor is overwritten with the
+// first 16 bytes read from src (via io.ReadFull, so a short read is
+// reported as an error rather than silently producing a wrong counter
+// value), and the internal cipher.Stream is re-initialized with it.
+//
+// written reports the number of bytes actually written to dst (the
+// plaintext), not the number of bytes read from src.
+//
+// The read buffer size is taken from the CopyBufferSize member variable.
+func (e *AESCTRDecryptor) Copy(dst io.Writer, src io.Reader) (written int64, err error) {
+	if _, err := io.ReadFull(src, e.iv); err != nil {
+		return 0, err
+	}
+	stream := cipher.NewCTR(e.block, e.iv)
+	e.process = stream.XORKeyStream
+
+	inputBuf := make([]byte, e.CopyBufferSize)
+	outputBuf := make([]byte, e.CopyBufferSize)
+
+	for {
+		n, rerr := src.Read(inputBuf)
+		if n > 0 {
+			if werr := writeAll(e.Write, inputBuf[:n]); werr != nil {
+				return written, werr
+			}
+			if derr := drainAvailable(dst, e.Read, outputBuf, &written); derr != nil {
+				return written, derr
+			}
+		}
+
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+
+	e.Close()
+	if err := drainAvailable(dst, e.Read, outputBuf, &written); err != nil {
+		return written, err
+	}
+
+	return written, nil
+}
@@ -0,0 +1,366 @@
+package aescbc
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+)
+
+// hmacTagSize is the number of bytes of the HMAC-SHA256 output that are
+// appended to the ciphertext as the authentication tag. Per RFC 7518's
+// A128CBC-HS256 construction, the tag is truncated to half of the HMAC
+// output.
+const hmacTagSize = sha256.Size / 2
+
+// hmacMasterSecretSize is the combined length of the MAC key and the AES-256
+// encryption key that NewAESCBCHMACEncryptor derives its keys from.
+const hmacMasterSecretSize = 32 + 32
+
+// macKeyAndEncKey splits a master secret into its MAC key (the first half)
+// and its AES encryption key (the second half), per RFC 7518.
+func macKeyAndEncKey(secret []byte) (macKey []byte, encKey []byte, err error) {
+	if len(secret) != hmacMasterSecretSize {
+		return nil, nil, errors.New("master secret must be 64 bytes long")
+	}
+	half := len(secret) / 2
+	return secret[:half], secret[half:], nil
+}
+
+// associatedDataLength is "AL" from RFC 7518 Section 5.2.2.1: the bit length
+// of the associated data, as a 64-bit big-endian integer. This package does
+// not currently support additional associated data, so AL is always zero.
+func associatedDataLength() []byte {
+	al := make([]byte, 8)
+	binary.BigEndian.PutUint64(al, 0)
+	return al
+}
+
+// The AESCBCHMACEncryptor type wraps AESCBCEncryptor in an Encrypt-then-MAC
+// construction, computing an HMAC-SHA256 tag over the IV and ciphertext so
+// that tampering can be detected before padding is ever removed on decrypt.
+// This closes the padding-oracle hole that a bare AESCBCDecryptor has: its
+// Close() reveals whether padding was valid, and an attacker who can make
+// repeated decryption attempts can exploit that to recover plaintext.
+//
+// This type should be created by NewAESCBCHMACEncryptor() or
+// NewAESCBCHMACEncryptorWithSecret() rather than by directly instantiating
+// the type in your code.
+//
+// Exported fields:
+// MasterSecret: the 64-byte secret that the MAC key and AES key are derived from
+// IV: the initialization vector used to initialize the AES cipher
+// CopyBufferSize: the size (in bytes) of the read/write buffer that Copy() will use
+type AESCBCHMACEncryptor struct {
+	MasterSecret   []byte
+	IV             []byte
+	CopyBufferSize int64
+	enc            *AESCBCEncryptor
+	mac            hash.Hash
+	outputoverflow []byte
+	isClosed       bool
+}
+
+// NewAESCBCHMACEncryptor returns an AESCBCHMACEncryptor with a randomly
+// generated MasterSecret. After calling it, the caller should copy
+// MasterSecret and IV so that they can be provided to the decrypting
+// application.
+func NewAESCBCHMACEncryptor(opts ...Option) (*AESCBCHMACEncryptor, error) {
+	secret := make([]byte, hmacMasterSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return NewAESCBCHMACEncryptorWithSecret(secret, opts...)
+}
+
+// NewAESCBCHMACEncryptorWithSecret is like NewAESCBCHMACEncryptor, but
+// derives its MAC and encryption keys from the supplied 64-byte secret
+// instead of generating one.
+func NewAESCBCHMACEncryptorWithSecret(secret []byte, opts ...Option) (*AESCBCHMACEncryptor, error) {
+	macKey, encKey, err := macKeyAndEncKey(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := newAESCBCEncryptorWithKey(encKey, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var e AESCBCHMACEncryptor
+	e.MasterSecret = secret
+	e.IV = enc.IV
+	e.enc = enc
+	e.CopyBufferSize = enc.CopyBufferSize
+	e.outputoverflow = make([]byte, 0)
+
+	//AL is appended once the ciphertext is complete, in Close(), since RFC
+	//7518's MAC input is IV || ciphertext || AL
+	e.mac = hmac.New(sha256.New, macKey)
+	e.mac.Write(e.IV)
+
+	return &e, nil
+}
+
+// Write implements io.Writer and accepts plaintext to be encrypted. After
+// writing plaintext to Write(), ciphertext will become available on the
+// Read() method.
+func (e *AESCBCHMACEncryptor) Write(p []byte) (n int, err error) {
+	if e.isClosed {
+		return 0, errors.New("writer has been closed")
+	}
+
+	if _, err := e.enc.Write(p); err != nil {
+		return 0, err
+	}
+	e.drainCiphertext()
+	return len(p), nil
+}
+
+// drainCiphertext reads any ciphertext the inner encryptor has produced,
+// folds it into the running MAC, and appends it to outputoverflow for the
+// caller to Read().
+func (e *AESCBCHMACEncryptor) drainCiphertext() {
+	buf := make([]byte, e.enc.CopyBufferSize)
+	for {
+		n, _ := e.enc.Read(buf)
+		if n == 0 {
+			return
+		}
+		e.mac.Write(buf[:n])
+		e.outputoverflow = append(e.outputoverflow, buf[:n]...)
+	}
+}
+
+// Read implements io.Reader and returns ciphertext that has been encrypted.
+// Once Close() has been called, the final bytes returned are the
+// authentication tag.
+func (e *AESCBCHMACEncryptor) Read(p []byte) (n int, err error) {
+	if e.isClosed && len(e.outputoverflow) == 0 {
+		return 0, io.EOF
+	}
+
+	if len(p) >= len(e.outputoverflow) {
+		n = copy(p, e.outputoverflow)
+		e.outputoverflow = e.outputoverflow[:0]
+		return n, nil
+	}
+
+	n = copy(p, e.outputoverflow)
+	e.outputoverflow = e.outputoverflow[n:]
+	return n, nil
+}
+
+// Close is used to signal no more data will be written to the Encryptor.
+// After Close() is called, the inner CBC encryptor's padded final block is
+// produced, the HMAC tag over IV || ciphertext || AL is computed, and the
+// tag is appended to the ciphertext available on Read().
+func (e *AESCBCHMACEncryptor) Close() {
+	e.enc.Close()
+	e.drainCiphertext()
+
+	e.mac.Write(associatedDataLength())
+	tag := e.mac.Sum(nil)[:hmacTagSize]
+	e.outputoverflow = append(e.outputoverflow, tag...)
+	e.isClosed = true
+}
+
+// Copy encrypts the data read from the io.Reader and writes it to the
+// io.Writer, producing the layout IV || ciphertext || tag.
+//
+// written reports the number of bytes actually written to dst, not the
+// number of bytes read from src.
+//
+// The read buffer size is taken from the CopyBufferSize member variable.
+func (e *AESCBCHMACEncryptor) Copy(dst io.Writer, src io.Reader) (written int64, err error) {
+	inputBuf := make([]byte, e.CopyBufferSize)
+	outputBuf := make([]byte, e.CopyBufferSize)
+
+	if err := writeFull(dst, e.IV, &written); err != nil {
+		return written, err
+	}
+
+	for {
+		n, rerr := src.Read(inputBuf)
+		if n > 0 {
+			if werr := writeAll(e.Write, inputBuf[:n]); werr != nil {
+				return written, werr
+			}
+			if derr := drainAvailable(dst, e.Read, outputBuf, &written); derr != nil {
+				return written, derr
+			}
+		}
+
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+
+	e.Close()
+	if err := drainAvailable(dst, e.Read, outputBuf, &written); err != nil {
+		return written, err
+	}
+
+	return written, nil
+}
+
+// The AESCBCHMACDecryptor type decrypts ciphertext produced by
+// AESCBCHMACEncryptor, verifying the HMAC-SHA256 tag before the inner CBC
+// decryptor ever touches the padding. On a verification failure, Close()
+// returns the same generic error that a padding failure would, so that the
+// two cases are indistinguishable to a caller probing for a padding oracle.
+//
+// This type should be created by NewAESCBCHMACDecryptor() rather than by
+// directly instantiating the type in your code.
+type AESCBCHMACDecryptor struct {
+	CopyBufferSize int64
+	dec            *AESCBCDecryptor
+	mac            hash.Hash
+	tagBuf         []byte
+	isClosed       bool
+}
+
+// errAuthFailure is returned for both a failed HMAC verification and a
+// failed padding check, so the two failure modes can't be distinguished
+// from the error alone.
+var errAuthFailure = errors.New("invalid padding")
+
+// NewAESCBCHMACDecryptor returns an AESCBCHMACDecryptor instance. secret must
+// be the same 64-byte MasterSecret, and iv the same IV, that were used to
+// encrypt the data.
+func NewAESCBCHMACDecryptor(secret []byte, iv []byte, opts ...Option) (*AESCBCHMACDecryptor, error) {
+	macKey, encKey, err := macKeyAndEncKey(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	dec, err := NewAESCBCDecryptor(encKey, iv, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var e AESCBCHMACDecryptor
+	e.dec = dec
+	e.CopyBufferSize = dec.CopyBufferSize
+	e.tagBuf = make([]byte, 0, hmacTagSize)
+
+	//AL is appended once the ciphertext is complete, in Close(), since RFC
+	//7518's MAC input is IV || ciphertext || AL
+	e.mac = hmac.New(sha256.New, macKey)
+	e.mac.Write(iv)
+
+	return &e, nil
+}
+
+// Write implements io.Writer and accepts ciphertext (followed, eventually,
+// by the trailing authentication tag) to be decrypted. The final
+// hmacTagSize bytes written are always held back, since they cannot be told
+// apart from ciphertext until Close() is called and no more data follows.
+func (e *AESCBCHMACDecryptor) Write(p []byte) (n int, err error) {
+	if e.isClosed {
+		return 0, errors.New("writer has been closed")
+	}
+
+	combined := append(e.tagBuf, p...)
+	if len(combined) <= hmacTagSize {
+		e.tagBuf = combined
+		return len(p), nil
+	}
+
+	flushable := combined[:len(combined)-hmacTagSize]
+	e.tagBuf = append([]byte(nil), combined[len(combined)-hmacTagSize:]...)
+
+	e.mac.Write(flushable)
+	if _, err := e.dec.Write(flushable); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Read implements io.Reader and returns plaintext that has been decrypted.
+func (e *AESCBCHMACDecryptor) Read(p []byte) (n int, err error) {
+	return e.dec.Read(p)
+}
+
+// Close verifies the HMAC tag held back by Write() against IV || ciphertext
+// || AL before removing padding from the buffered plaintext. If the tag does
+// not match, or if the padding turns out to be invalid, the same generic
+// error is returned either way.
+func (e *AESCBCHMACDecryptor) Close() error {
+	if len(e.tagBuf) != hmacTagSize {
+		return errAuthFailure
+	}
+
+	e.mac.Write(associatedDataLength())
+	expectedTag := e.mac.Sum(nil)[:hmacTagSize]
+	if !hmac.Equal(e.tagBuf, expectedTag) {
+		return errAuthFailure
+	}
+
+	if err := e.dec.Close(); err != nil {
+		return errAuthFailure
+	}
+
+	e.isClosed = true
+	return nil
+}
+
+// Copy decrypts the data read from the io.Reader and writes it to the
+// io.Writer. src is expected to contain the layout IV || ciphertext || tag;
+// the first 16 bytes are consumed as the IV (via io.ReadFull, so a short
+// read is reported as an error) and the trailing hmacTagSize bytes are
+// buffered internally by Write() until Close() can verify them.
+//
+// written reports the number of bytes actually written to dst (the
+// plaintext), not the number of bytes read from src.
+//
+// The read buffer size is taken from the CopyBufferSize member variable.
+func (e *AESCBCHMACDecryptor) Copy(dst io.Writer, src io.Reader) (written int64, err error) {
+	if _, err := io.ReadFull(src, e.dec.iv); err != nil {
+		return 0, err
+	}
+	e.dec.cbc = cipher.NewCBCDecrypter(e.dec.cipher, e.dec.iv)
+
+	//Re-seed the running MAC with IV now that the real IV has arrived; AL
+	//is appended in Close() once the ciphertext is complete
+	e.mac.Reset()
+	e.mac.Write(e.dec.iv)
+
+	inputBuf := make([]byte, e.CopyBufferSize)
+	outputBuf := make([]byte, e.CopyBufferSize)
+
+	for {
+		n, rerr := src.Read(inputBuf)
+		if n > 0 {
+			if werr := writeAll(e.Write, inputBuf[:n]); werr != nil {
+				return written, werr
+			}
+			if derr := drainAvailable(dst, e.Read, outputBuf, &written); derr != nil {
+				return written, derr
+			}
+		}
+
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		return written, err
+	}
+	if err := drainAvailable(dst, e.Read, outputBuf, &written); err != nil {
+		return written, err
+	}
+
+	return written, nil
+}
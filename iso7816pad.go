@@ -0,0 +1,40 @@
+package aescbc
+
+import "errors"
+
+// Iso7816Padder implements Padder using the ISO/IEC 7816-4 padding scheme:
+// a single 0x80 byte followed by zero bytes out to the block boundary.
+type Iso7816Padder struct{}
+
+// Pad implements Padder.
+func (Iso7816Padder) Pad(block []byte, blockSize int) []byte {
+	r := len(block) % blockSize
+	pl := blockSize - r
+
+	padded := make([]byte, len(block), len(block)+pl)
+	copy(padded, block)
+
+	padded = append(padded, 0x80)
+	for i := 1; i < pl; i++ {
+		padded = append(padded, 0x00)
+	}
+	return padded
+}
+
+// Unpad implements Padder.
+func (Iso7816Padder) Unpad(block []byte) ([]byte, error) {
+	if len(block) == 0 {
+		return nil, nil
+	}
+
+	i := len(block) - 1
+	for i >= 0 && block[i] == 0x00 {
+		i--
+	}
+
+	if i < 0 || block[i] != 0x80 {
+		return nil, errors.New("invalid padding")
+	}
+
+	return block[:i], nil
+}
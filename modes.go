@@ -0,0 +1,44 @@
+package aescbc
+
+import "io"
+
+// Encryptor is implemented by every encryption type in this package
+// (AESCBCEncryptor, AESCFBEncryptor, AESOFBEncryptor, AESCTREncryptor,
+// AESCBCHMACEncryptor, and OpenSSLEncryptor). Plaintext is written via Write
+// and the corresponding ciphertext becomes available on Read. Close signals
+// that no more plaintext will be written.
+type Encryptor interface {
+	io.ReadWriter
+	Close()
+	Copy(dst io.Writer, src io.Reader) (written int64, err error)
+}
+
+// Decryptor is implemented by every decryption type in this package
+// (AESCBCDecryptor, AESCFBDecryptor, AESOFBDecryptor, AESCTRDecryptor,
+// AESCBCHMACDecryptor, and OpenSSLDecryptor). Ciphertext is written via
+// Write and the corresponding plaintext becomes available on Read. Close
+// signals that no more ciphertext will be written.
+type Decryptor interface {
+	io.ReadWriter
+	Close() error
+	Copy(dst io.Writer, src io.Reader) (written int64, err error)
+}
+
+// The following compile-time assertions keep the lists above honest: if a
+// new Encryptor/Decryptor-shaped type is added to the package without being
+// added here too, it's a sign the doc comments above need updating as well.
+var (
+	_ Encryptor = (*AESCBCEncryptor)(nil)
+	_ Encryptor = (*AESCFBEncryptor)(nil)
+	_ Encryptor = (*AESOFBEncryptor)(nil)
+	_ Encryptor = (*AESCTREncryptor)(nil)
+	_ Encryptor = (*AESCBCHMACEncryptor)(nil)
+	_ Encryptor = (*OpenSSLEncryptor)(nil)
+
+	_ Decryptor = (*AESCBCDecryptor)(nil)
+	_ Decryptor = (*AESCFBDecryptor)(nil)
+	_ Decryptor = (*AESOFBDecryptor)(nil)
+	_ Decryptor = (*AESCTRDecryptor)(nil)
+	_ Decryptor = (*AESCBCHMACDecryptor)(nil)
+	_ Decryptor = (*OpenSSLDecryptor)(nil)
+)
@@ -0,0 +1,266 @@
+package aescbc
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// opensslMagic is the 8-byte ASCII header that `openssl enc` writes at the
+// start of a salted ciphertext stream.
+const opensslMagic = "Salted__"
+
+// opensslSaltSize is the length, in bytes, of the random salt that follows
+// opensslMagic in the stream.
+const opensslSaltSize = 8
+
+// opensslHeaderSize is the combined length of opensslMagic and the salt.
+const opensslHeaderSize = len(opensslMagic) + opensslSaltSize
+
+// The OpenSSLEncryptor type encrypts plaintext to the on-disk format
+// produced by `openssl enc -aes-256-cbc`: the header `Salted__`, an 8-byte
+// random salt, and AES-256-CBC ciphertext with PKCS7 padding. The AES key
+// and IV are derived from a passphrase and the salt, so unlike
+// AESCBCEncryptor, no key material needs to be exchanged out of band.
+//
+// This type should be created by NewOpenSSLEncryptor() rather than by
+// directly instantiating the type in your code.
+type OpenSSLEncryptor struct {
+	CopyBufferSize int64
+	enc            *AESCBCEncryptor
+	salt           []byte
+}
+
+// NewOpenSSLEncryptor returns an OpenSSLEncryptor that derives its AES key
+// and IV from passphrase and a freshly generated random salt. By default the
+// derivation is PBKDF2-HMAC-SHA256 with 10000 iterations, matching modern
+// `openssl enc -pbkdf2`; pass WithIterations() or WithEVPBytesToKey() to
+// change that.
+func NewOpenSSLEncryptor(passphrase string, opts ...OpenSSLOption) (*OpenSSLEncryptor, error) {
+	o := defaultOpenSSLOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	salt := make([]byte, opensslSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key, iv := deriveKeyAndIV([]byte(passphrase), salt, o)
+
+	enc, err := newAESCBCEncryptorWithKeyAndIV(key, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	var e OpenSSLEncryptor
+	e.enc = enc
+	e.salt = salt
+	e.CopyBufferSize = enc.CopyBufferSize
+	return &e, nil
+}
+
+// header returns the Salted__ + salt bytes that must precede the ciphertext
+// in the output stream.
+func (e *OpenSSLEncryptor) header() []byte {
+	h := make([]byte, 0, opensslHeaderSize)
+	h = append(h, opensslMagic...)
+	h = append(h, e.salt...)
+	return h
+}
+
+// Write implements io.Writer and accepts plaintext to be encrypted. After
+// writing plaintext to Write(), ciphertext will become available on the
+// Read() method. Write() does not emit the Salted__ header and salt; use
+// Copy() if you need the full on-disk format written for you.
+func (e *OpenSSLEncryptor) Write(p []byte) (n int, err error) {
+	return e.enc.Write(p)
+}
+
+// Read implements io.Reader and returns ciphertext that has been encrypted.
+func (e *OpenSSLEncryptor) Read(p []byte) (n int, err error) {
+	return e.enc.Read(p)
+}
+
+// Close is used to signal no more data will be written to the Encryptor.
+func (e *OpenSSLEncryptor) Close() {
+	e.enc.Close()
+}
+
+// Copy encrypts the data read from the io.Reader and writes it to the
+// io.Writer in the `openssl enc` on-disk format: Salted__, the salt, then
+// AES-256-CBC ciphertext.
+//
+// The read buffer size is taken from the CopyBufferSize member variable.
+func (e *OpenSSLEncryptor) Copy(dst io.Writer, src io.Reader) (written int64, err error) {
+	inputBuf := make([]byte, e.CopyBufferSize)
+	outputBuf := make([]byte, e.CopyBufferSize)
+
+	//Write the Salted__ header and salt to the output stream
+	if err := writeFull(dst, e.header(), &written); err != nil {
+		return written, err
+	}
+
+	for {
+		n, rerr := src.Read(inputBuf)
+		if n > 0 {
+			if werr := writeAll(e.Write, inputBuf[:n]); werr != nil {
+				return written, werr
+			}
+			if derr := drainAvailable(dst, e.Read, outputBuf, &written); derr != nil {
+				return written, derr
+			}
+		}
+
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+
+	e.Close()
+	if err := drainAvailable(dst, e.Read, outputBuf, &written); err != nil {
+		return written, err
+	}
+	return written, nil
+}
+
+// The OpenSSLDecryptor type decrypts ciphertext produced by
+// `openssl enc -aes-256-cbc` (or by OpenSSLEncryptor): it validates the
+// Salted__ header, reads the salt, and derives the AES key and IV from a
+// passphrase before initializing its internal cipher.BlockMode.
+//
+// This type should be created by NewOpenSSLDecryptor() rather than by
+// directly instantiating the type in your code.
+type OpenSSLDecryptor struct {
+	CopyBufferSize int64
+	passphrase     []byte
+	opensslOpts    *opensslOptions
+	dec            *AESCBCDecryptor
+	headerBuf      []byte
+	isClosed       bool
+}
+
+// NewOpenSSLDecryptor returns an OpenSSLDecryptor that will derive its AES
+// key and IV from passphrase once the Salted__ header and salt have been
+// read from the ciphertext stream. Pass WithEVPBytesToKey() if the
+// ciphertext was produced by a version of `openssl enc` that predates
+// PBKDF2 support.
+func NewOpenSSLDecryptor(passphrase string, opts ...OpenSSLOption) (*OpenSSLDecryptor, error) {
+	o := defaultOpenSSLOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var e OpenSSLDecryptor
+	e.passphrase = []byte(passphrase)
+	e.opensslOpts = o
+	e.headerBuf = make([]byte, 0, opensslHeaderSize)
+	e.CopyBufferSize = 5 * 1024 * 1024
+	return &e, nil
+}
+
+// Write implements io.Writer and accepts ciphertext to be decrypted. The
+// first opensslHeaderSize bytes written are buffered and interpreted as the
+// Salted__ header and salt; only once they have arrived is the AES key and
+// IV derived and the internal cipher.BlockMode initialized. Plaintext then
+// becomes available on the Read() method as further ciphertext is written.
+func (e *OpenSSLDecryptor) Write(p []byte) (n int, err error) {
+	if e.isClosed {
+		return 0, errors.New("writer has been closed")
+	}
+	total := len(p)
+
+	if e.dec == nil {
+		needed := opensslHeaderSize - len(e.headerBuf)
+		if needed > len(p) {
+			e.headerBuf = append(e.headerBuf, p...)
+			return total, nil
+		}
+
+		e.headerBuf = append(e.headerBuf, p[:needed]...)
+		p = p[needed:]
+
+		if string(e.headerBuf[:len(opensslMagic)]) != opensslMagic {
+			return 0, errors.New("not an OpenSSL enc stream: missing Salted__ header")
+		}
+		salt := e.headerBuf[len(opensslMagic):opensslHeaderSize]
+
+		key, iv := deriveKeyAndIV(e.passphrase, salt, e.opensslOpts)
+
+		dec, err := NewAESCBCDecryptor(key, iv)
+		if err != nil {
+			return 0, err
+		}
+		e.dec = dec
+	}
+
+	if len(p) == 0 {
+		return total, nil
+	}
+
+	if _, err := e.dec.Write(p); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Read implements io.Reader and returns plaintext that has been decrypted.
+// Until the Salted__ header has been fully read by Write(), Read() returns
+// a zero-byte slice and a nil error.
+func (e *OpenSSLDecryptor) Read(p []byte) (n int, err error) {
+	if e.dec == nil {
+		return 0, nil
+	}
+	return e.dec.Read(p)
+}
+
+// Close is used to signal no more data will be written to the Decryptor.
+func (e *OpenSSLDecryptor) Close() error {
+	if e.dec == nil {
+		return errors.New("not enough bytes in read buffer to read the Salted__ header")
+	}
+	e.isClosed = true
+	return e.dec.Close()
+}
+
+// Copy decrypts the data read from the io.Reader and writes it to the
+// io.Writer. src is expected to start with the Salted__ header and salt
+// written by OpenSSLEncryptor.Copy() or by `openssl enc`.
+//
+// The read buffer size is taken from the CopyBufferSize member variable.
+func (e *OpenSSLDecryptor) Copy(dst io.Writer, src io.Reader) (written int64, err error) {
+	inputBuf := make([]byte, e.CopyBufferSize)
+	outputBuf := make([]byte, e.CopyBufferSize)
+
+	for {
+		n, rerr := src.Read(inputBuf)
+		if n > 0 {
+			if werr := writeAll(e.Write, inputBuf[:n]); werr != nil {
+				return written, werr
+			}
+			if derr := drainAvailable(dst, e.Read, outputBuf, &written); derr != nil {
+				return written, derr
+			}
+		}
+
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		return written, err
+	}
+	if err := drainAvailable(dst, e.Read, outputBuf, &written); err != nil {
+		return written, err
+	}
+
+	return written, nil
+}
@@ -0,0 +1,76 @@
+package aescbc
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// openSSLKeyIVSize is the combined length of the AES-256 key (32 bytes) and
+// IV (16 bytes) that the `openssl enc -aes-256-cbc` format derives from a
+// passphrase and salt.
+const openSSLKeyIVSize = 32 + 16
+
+// deriveKeyAndIV derives the AES key and IV used by the OpenSSL `enc`
+// on-disk format from a passphrase and salt, using either PBKDF2-HMAC-SHA256
+// (the modern default) or the legacy EVP_BytesToKey derivation.
+func deriveKeyAndIV(passphrase []byte, salt []byte, o *opensslOptions) (key []byte, iv []byte) {
+	var material []byte
+	if o.legacyKDF {
+		material = evpBytesToKey(passphrase, salt, openSSLKeyIVSize)
+	} else {
+		material = pbkdf2HMACSHA256(passphrase, salt, o.iterations, openSSLKeyIVSize)
+	}
+	return material[:32], material[32:openSSLKeyIVSize]
+}
+
+// pbkdf2HMACSHA256 implements PBKDF2 (RFC 8018) using HMAC-SHA256 as the
+// pseudorandom function, matching `openssl enc -pbkdf2`.
+func pbkdf2HMACSHA256(password []byte, salt []byte, iterations int, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	dk := make([]byte, 0, numBlocks*hashLen)
+	blockIndex := make([]byte, 4)
+	for block := 1; block <= numBlocks; block++ {
+		binary.BigEndian.PutUint32(blockIndex, uint32(block))
+
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(blockIndex)
+		u := prf.Sum(nil)
+
+		t := make([]byte, len(u))
+		copy(t, u)
+
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(nil)
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+
+		dk = append(dk, t...)
+	}
+	return dk[:keyLen]
+}
+
+// evpBytesToKey implements OpenSSL's legacy, MD5-based EVP_BytesToKey key
+// derivation: D_i = MD5(D_{i-1} || password || salt), with D_0 empty,
+// concatenating successive D_i until keyLen bytes are available.
+func evpBytesToKey(password []byte, salt []byte, keyLen int) []byte {
+	var dk, prev []byte
+	for len(dk) < keyLen {
+		h := md5.New()
+		h.Write(prev)
+		h.Write(password)
+		h.Write(salt)
+		prev = h.Sum(nil)
+		dk = append(dk, prev...)
+	}
+	return dk[:keyLen]
+}
@@ -0,0 +1,39 @@
+package aescbc
+
+// defaultPBKDF2Iterations matches the default iteration count used by
+// modern versions of `openssl enc -pbkdf2`.
+const defaultPBKDF2Iterations = 10000
+
+// OpenSSLOption configures an OpenSSLEncryptor or OpenSSLDecryptor at
+// construction time.
+type OpenSSLOption func(*opensslOptions)
+
+type opensslOptions struct {
+	iterations int
+	legacyKDF  bool
+}
+
+func defaultOpenSSLOptions() *opensslOptions {
+	return &opensslOptions{
+		iterations: defaultPBKDF2Iterations,
+	}
+}
+
+// WithIterations overrides the PBKDF2 iteration count used to derive the AES
+// key and IV from the passphrase. It has no effect if WithEVPBytesToKey is
+// also used, since EVP_BytesToKey is not iterated by a configurable count.
+func WithIterations(n int) OpenSSLOption {
+	return func(o *opensslOptions) {
+		o.iterations = n
+	}
+}
+
+// WithEVPBytesToKey selects the legacy, MD5-based EVP_BytesToKey key
+// derivation instead of PBKDF2-HMAC-SHA256. Files produced by older
+// versions of the `openssl enc` command (before PBKDF2 became the default)
+// require this option to decrypt correctly.
+func WithEVPBytesToKey() OpenSSLOption {
+	return func(o *opensslOptions) {
+		o.legacyKDF = true
+	}
+}
@@ -0,0 +1,38 @@
+package aescbc
+
+// Padder is implemented by the padding schemes that AESCBCEncryptor and
+// AESCBCDecryptor use to align plaintext to the AES block size before
+// encryption, and to remove that padding after decryption.
+//
+// Pad receives the final, possibly-partial block of plaintext along with
+// the cipher's block size and returns a full block (or blocks) with padding
+// appended. Unpad receives the final decrypted block(s) and returns the
+// plaintext with padding stripped, or an error if the padding is malformed.
+type Padder interface {
+	Pad(block []byte, blockSize int) []byte
+	Unpad(block []byte) ([]byte, error)
+}
+
+// Option configures an AESCBCEncryptor or AESCBCDecryptor at construction
+// time. Options are applied in order by NewAESCBCEncryptor,
+// NewAESCBCEncryptorWithKeySize, and NewAESCBCDecryptor.
+type Option func(*options)
+
+type options struct {
+	padder Padder
+}
+
+func defaultOptions() *options {
+	return &options{
+		padder: Pkcs7Padder{},
+	}
+}
+
+// WithPadder overrides the default PKCS7 padding scheme. This is useful
+// when interoperating with ciphertext produced by tools that pad using
+// ISO/IEC 7816-4 (Iso7816Padder) or ANSI X.923 (AnsiX923Padder) instead.
+func WithPadder(p Padder) Option {
+	return func(o *options) {
+		o.padder = p
+	}
+}
@@ -8,6 +8,21 @@ import (
 	"errors"
 )
 
+// Pkcs7Padder implements Padder using the PKCS7 padding scheme, as
+// described in RFC 5652. This is the default padding scheme used by
+// AESCBCEncryptor and AESCBCDecryptor.
+type Pkcs7Padder struct{}
+
+// Pad implements Padder.
+func (Pkcs7Padder) Pad(block []byte, blockSize int) []byte {
+	return Pkcs7Pad(block, blockSize)
+}
+
+// Unpad implements Padder.
+func (Pkcs7Padder) Unpad(block []byte) ([]byte, error) {
+	return Pkcs7Unpad(block)
+}
+
 func Pkcs7Pad(input []byte, blockSize int) []byte {
 	r := len(input) % blockSize
 	pl := blockSize - r
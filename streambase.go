@@ -0,0 +1,71 @@
+package aescbc
+
+import (
+	"errors"
+	"io"
+)
+
+// streamBase holds the Write/Read/Close buffering machinery shared by the
+// stream-mode encryptors and decryptors (CFB, OFB, CTR).
+//
+// Unlike CBC, these modes operate on a cipher.Stream rather than a
+// cipher.BlockMode: every byte written can be processed and made available
+// for reading immediately, with no requirement to hold back a partial or
+// final block for padding. So, unlike the CBC types, streamBase has no
+// inputoverflow buffer and its close() is just a flag flip.
+type streamBase struct {
+	CopyBufferSize int64
+	outputoverflow []byte
+	isClosed       bool
+	process        func(dst, src []byte)
+}
+
+func newStreamBase(process func(dst, src []byte)) streamBase {
+	return streamBase{
+		//Provide a default copy block size of 5MB
+		CopyBufferSize: 5 * 1024 * 1024,
+		outputoverflow: make([]byte, 0),
+		process:        process,
+	}
+}
+
+// write runs p through the mode-specific block processor and appends the
+// result to the output buffer. It implements the Write half of io.ReadWriter
+// for the embedding type.
+func (s *streamBase) write(p []byte) (n int, err error) {
+	if s.isClosed {
+		return 0, errors.New("writer has been closed")
+	}
+
+	out := make([]byte, len(p))
+	s.process(out, p)
+	s.outputoverflow = append(s.outputoverflow, out...)
+	return len(p), nil
+}
+
+// read drains the output buffer into p. It implements the Read half of
+// io.ReadWriter for the embedding type.
+func (s *streamBase) read(p []byte) (n int, err error) {
+	if s.isClosed && len(s.outputoverflow) == 0 {
+		return 0, io.EOF
+	}
+
+	if len(p) >= len(s.outputoverflow) {
+		//We can send all of our data to the caller
+		n = copy(p, s.outputoverflow)
+		s.outputoverflow = s.outputoverflow[:0]
+		return n, nil
+	}
+
+	//We can only return some of our waiting data
+	n = copy(p, s.outputoverflow)
+	s.outputoverflow = s.outputoverflow[n:]
+	return n, nil
+}
+
+// close marks the stream as closed. No padding step is needed: stream
+// modes have no alignment constraint, so every byte written has already
+// been processed and placed in the output buffer.
+func (s *streamBase) close() {
+	s.isClosed = true
+}